@@ -0,0 +1,145 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ColorSpec describes the color a schedule entry should resolve to.
+// Exactly one of Color, Hue/Saturation or ColorTemperature is expected to
+// be set; Color accepts either a named color ("orange") or a hex RGB
+// triplet ("#ff8800").
+type ColorSpec struct {
+	ColorTemperature int    `json:"colorTemperature,omitempty"`
+	Color            string `json:"color,omitempty"`
+	Hue              uint16 `json:"hue,omitempty"`
+	Saturation       uint8  `json:"saturation,omitempty"`
+}
+
+// resolve converts the spec into the LightState color representation the
+// bridge should receive, leaving Brightness and TransitionTime to the
+// caller.
+func (spec ColorSpec) resolve() (LightState, error) {
+	switch {
+	case strings.HasPrefix(spec.Color, "#"):
+		xy, err := parseHexColor(spec.Color)
+		if err != nil {
+			return LightState{}, err
+		}
+		return LightState{ColorMode: ColorModeXY, XY: xy}, nil
+	case spec.Color != "":
+		hue, saturation, err := parseColorName(spec.Color)
+		if err != nil {
+			return LightState{}, err
+		}
+		return LightState{ColorMode: ColorModeHueSaturation, Hue: hue, Saturation: saturation}, nil
+	case spec.Hue != 0 || spec.Saturation != 0:
+		return LightState{ColorMode: ColorModeHueSaturation, Hue: spec.Hue, Saturation: spec.Saturation}, nil
+	default:
+		return LightState{ColorMode: ColorModeColorTemperature, ColorTemperature: spec.ColorTemperature}, nil
+	}
+}
+
+// namedColors maps common color names to their position on the Hue
+// hue/saturation wheel, so schedule authors can write "orange" instead
+// of raw hue and saturation values.
+var namedColors = map[string]struct {
+	Hue        uint16
+	Saturation uint8
+}{
+	"red":    {0, 254},
+	"orange": {5461, 254},
+	"yellow": {10921, 200},
+	"green":  {25500, 254},
+	"cyan":   {36045, 254},
+	"blue":   {46920, 254},
+	"purple": {50000, 254},
+	"pink":   {56100, 180},
+	"white":  {0, 0},
+}
+
+// parseColorName resolves a named color to a hue/saturation pair.
+func parseColorName(name string) (hue uint16, saturation uint8, err error) {
+	color, found := namedColors[strings.ToLower(name)]
+	if !found {
+		return 0, 0, fmt.Errorf("unknown color name %q", name)
+	}
+	return color.Hue, color.Saturation, nil
+}
+
+// parseHexColor converts a "#rrggbb" string into CIE xy coordinates, the
+// color space the Hue API's xy attribute expects.
+func parseHexColor(hex string) (xy [2]float32, err error) {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return xy, fmt.Errorf("invalid hex color %q", hex)
+	}
+
+	r, err := hexComponent(hex[0:2])
+	if err != nil {
+		return xy, err
+	}
+	g, err := hexComponent(hex[2:4])
+	if err != nil {
+		return xy, err
+	}
+	b, err := hexComponent(hex[4:6])
+	if err != nil {
+		return xy, err
+	}
+
+	return rgbToXY(r, g, b), nil
+}
+
+func hexComponent(s string) (float64, error) {
+	value, err := strconv.ParseUint(s, 16, 8)
+	if err != nil {
+		return 0, err
+	}
+	return float64(value) / 255, nil
+}
+
+// rgbToXY converts gamma-corrected sRGB (each channel 0-1) to the CIE xy
+// color space, following Philips' own conversion formula for Hue bulbs.
+func rgbToXY(r float64, g float64, b float64) [2]float32 {
+	linearize := func(c float64) float64 {
+		if c > 0.04045 {
+			return math.Pow((c+0.055)/1.055, 2.4)
+		}
+		return c / 12.92
+	}
+	r, g, b = linearize(r), linearize(g), linearize(b)
+
+	x := r*0.664511 + g*0.154324 + b*0.162028
+	y := r*0.283881 + g*0.668433 + b*0.047685
+	z := r*0.000088 + g*0.072310 + b*0.986039
+
+	sum := x + y + z
+	if sum == 0 {
+		return [2]float32{0, 0}
+	}
+	return [2]float32{float32(x / sum), float32(y / sum)}
+}
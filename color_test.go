@@ -0,0 +1,86 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import "testing"
+
+func TestColorSpecResolveHex(t *testing.T) {
+	state, err := ColorSpec{Color: "#ff8800"}.resolve()
+	if err != nil {
+		t.Fatalf("resolve() returned error: %v", err)
+	}
+	if state.ColorMode != ColorModeXY {
+		t.Fatalf("expected ColorModeXY, got %v", state.ColorMode)
+	}
+	if state.XY[0] == 0 && state.XY[1] == 0 {
+		t.Fatalf("expected non-zero xy coordinates, got %v", state.XY)
+	}
+}
+
+func TestColorSpecResolveName(t *testing.T) {
+	state, err := ColorSpec{Color: "orange"}.resolve()
+	if err != nil {
+		t.Fatalf("resolve() returned error: %v", err)
+	}
+	if state.ColorMode != ColorModeHueSaturation {
+		t.Fatalf("expected ColorModeHueSaturation, got %v", state.ColorMode)
+	}
+	if state.Hue != namedColors["orange"].Hue || state.Saturation != namedColors["orange"].Saturation {
+		t.Fatalf("expected orange's hue/saturation, got hue=%v saturation=%v", state.Hue, state.Saturation)
+	}
+}
+
+func TestColorSpecResolveUnknownName(t *testing.T) {
+	_, err := ColorSpec{Color: "chartreuse"}.resolve()
+	if err == nil {
+		t.Fatal("expected an error for an unknown color name")
+	}
+}
+
+func TestColorSpecResolveHueSaturation(t *testing.T) {
+	state, err := ColorSpec{Hue: 100, Saturation: 200}.resolve()
+	if err != nil {
+		t.Fatalf("resolve() returned error: %v", err)
+	}
+	if state.ColorMode != ColorModeHueSaturation || state.Hue != 100 || state.Saturation != 200 {
+		t.Fatalf("expected hue=100 saturation=200, got %+v", state)
+	}
+}
+
+func TestColorSpecResolveColorTemperature(t *testing.T) {
+	state, err := ColorSpec{ColorTemperature: 300}.resolve()
+	if err != nil {
+		t.Fatalf("resolve() returned error: %v", err)
+	}
+	if state.ColorMode != ColorModeColorTemperature || state.ColorTemperature != 300 {
+		t.Fatalf("expected colorTemperature=300, got %+v", state)
+	}
+}
+
+func TestParseHexColorInvalid(t *testing.T) {
+	if _, err := parseHexColor("#ff88"); err == nil {
+		t.Fatal("expected an error for a short hex string")
+	}
+	if _, err := parseHexColor("#gggggg"); err == nil {
+		t.Fatal("expected an error for a non-hex string")
+	}
+}
@@ -0,0 +1,51 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+// Driver abstracts the smart bulb protocol a light is controlled through, so
+// Light and the scheduler aren't hardwired to Philips Hue. HueLight is the
+// original implementation; lifx.go adds a second one for LIFX bulbs.
+type Driver interface {
+	// SetState sets the light's brightness and color, transitioning over
+	// state.TransitionTime (or the driver's own default).
+	SetState(state LightState) error
+	// CurrentState returns the state the driver last observed for the light.
+	CurrentState() LightState
+	// HasChanged reports whether the light was changed since Kelvin last
+	// set its state, e.g. through a wall switch or a voice assistant.
+	HasChanged() bool
+	// Reachable reports whether the light currently responds to commands.
+	Reachable() bool
+	// On reports whether the light is currently switched on.
+	On() bool
+	// Capabilities reports which color representations the light supports.
+	Capabilities() Capabilities
+}
+
+// driverName identifies which Driver implementation a light in the
+// configuration should be controlled through.
+type driverName string
+
+const (
+	driverHue  driverName = "hue"
+	driverLifx driverName = "lifx"
+)
@@ -0,0 +1,228 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/Sirupsen/logrus"
+	hue "github.com/stefanwichmann/go.hue"
+)
+
+// eventStreamReconnectDelay is the time to wait before trying to
+// re-establish a dropped connection to the bridge event stream.
+const eventStreamReconnectDelay = 5 * time.Second
+
+// bridgeEvent mirrors the subset of the Hue Bridge v2 CLIP event stream
+// payload Kelvin cares about (resource type "light").
+type bridgeEvent struct {
+	Type string `json:"type"`
+	Data []struct {
+		Owner struct {
+			Rid string `json:"rid"`
+		} `json:"owner"`
+		On *struct {
+			On bool `json:"on"`
+		} `json:"on"`
+		Dimming *struct {
+			Brightness float64 `json:"brightness"`
+		} `json:"dimming"`
+		ColorTemperature *struct {
+			Mirek int `json:"mirek"`
+		} `json:"color_temperature"`
+	} `json:"data"`
+}
+
+// EventStream subscribes to a Hue Bridge v2 CLIP event stream and pushes
+// light attribute changes to per-light channels as they arrive, so Kelvin
+// can react to manual changes immediately instead of waiting for the next
+// polling cycle. Bridges that only support the v1 API never send anything
+// on this stream, and callers are expected to keep polling until the first
+// event arrives.
+type EventStream struct {
+	address     string
+	username    string
+	client      *http.Client
+	mutex       sync.Mutex
+	subscribers map[string]chan hue.LightAttributes
+	stop        chan struct{}
+}
+
+// NewEventStream creates an EventStream for the bridge at the given
+// address, authenticated with username.
+func NewEventStream(address string, username string) *EventStream {
+	return &EventStream{
+		address:  address,
+		username: username,
+		client: &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true}, // Hue bridges use self-signed certificates.
+			},
+		},
+		subscribers: make(map[string]chan hue.LightAttributes),
+		stop:        make(chan struct{}),
+	}
+}
+
+// Subscribe registers a channel that receives pushed attribute updates for
+// the light with the given v2 resource ID (light.rid as reported by the
+// bridge). The channel is buffered so a slow consumer never blocks the
+// stream.
+func (stream *EventStream) Subscribe(lightResourceID string) chan hue.LightAttributes {
+	stream.mutex.Lock()
+	defer stream.mutex.Unlock()
+
+	channel := make(chan hue.LightAttributes, 1)
+	stream.subscribers[lightResourceID] = channel
+	return channel
+}
+
+// Start connects to the bridge event stream in the background and keeps
+// reconnecting until Stop is called.
+func (stream *EventStream) Start() {
+	go func() {
+		for {
+			select {
+			case <-stream.stop:
+				return
+			default:
+			}
+
+			err := stream.connect()
+			if err != nil {
+				log.Printf("📡 Event stream - Connection to bridge %v failed: %v. Reconnecting in %v...", stream.address, err, eventStreamReconnectDelay)
+			}
+
+			select {
+			case <-stream.stop:
+				return
+			case <-time.After(eventStreamReconnectDelay):
+			}
+		}
+	}()
+}
+
+// Stop terminates the event stream connection and all future reconnect
+// attempts.
+func (stream *EventStream) Stop() {
+	close(stream.stop)
+}
+
+// SubscribeLights creates and starts the event stream for bridge and
+// subscribes every Hue-driven light with a known v2 resource ID to it, so
+// manual changes are reflected immediately instead of waiting for the next
+// polling cycle. It should be called once during startup, after lights
+// have been discovered from the bridge and their HueLight.ResourceID set.
+func SubscribeLights(bridge *hue.Bridge, lights []*Light) *EventStream {
+	stream := NewEventStream(bridge.IpAddr, bridge.Username)
+	stream.Start()
+
+	for _, light := range lights {
+		hueLight, ok := light.Driver.(*HueLight)
+		if !ok || hueLight.ResourceID == "" {
+			continue
+		}
+		light.subscribeToEventStream(stream, hueLight.ResourceID)
+	}
+
+	return stream
+}
+
+func (stream *EventStream) connect() error {
+	request, err := http.NewRequest("GET", fmt.Sprintf("https://%s/eventstream/clip/v2", stream.address), nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("hue-application-key", stream.username)
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := stream.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %v", response.StatusCode)
+	}
+
+	log.Printf("📡 Event stream - Connected to bridge %v", stream.address)
+
+	scanner := bufio.NewScanner(response.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) < 6 || line[:5] != "data:" {
+			continue // Ignore SSE comments, ids and blank lines.
+		}
+
+		var events []bridgeEvent
+		if err := json.Unmarshal([]byte(line[5:]), &events); err != nil {
+			log.Debugf("📡 Event stream - Failed to parse event: %v", err)
+			continue
+		}
+		stream.dispatch(events)
+	}
+	return scanner.Err()
+}
+
+func (stream *EventStream) dispatch(events []bridgeEvent) {
+	for _, event := range events {
+		if event.Type != "update" {
+			continue
+		}
+
+		for _, update := range event.Data {
+			stream.mutex.Lock()
+			channel, subscribed := stream.subscribers[update.Owner.Rid]
+			stream.mutex.Unlock()
+			if !subscribed {
+				continue
+			}
+
+			var attr hue.LightAttributes
+			attr.State.Reachable = true
+			if update.On != nil {
+				attr.State.On = update.On.On
+			}
+			if update.Dimming != nil {
+				attr.State.Bri = int(update.Dimming.Brightness / 100 * 254)
+			}
+			if update.ColorTemperature != nil && update.ColorTemperature.Mirek > 0 {
+				attr.State.Ct = update.ColorTemperature.Mirek
+			}
+
+			select {
+			case channel <- attr:
+			default:
+				// The light isn't listening right now (still on its previous
+				// update). Drop it rather than block the stream - the next
+				// polling cycle will pick up the current state anyway.
+			}
+		}
+	}
+}
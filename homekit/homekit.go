@@ -0,0 +1,148 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package homekit exposes Kelvin's managed lights as HomeKit accessories,
+// so they can be controlled through Siri or the Home app and manual
+// changes are picked up the same way a change through the Hue app is.
+package homekit
+
+import (
+	"fmt"
+
+	"github.com/brutella/hc"
+	"github.com/brutella/hc/accessory"
+	"github.com/brutella/hc/characteristic"
+
+	log "github.com/Sirupsen/logrus"
+)
+
+// Light is the subset of Kelvin's Light type a HomeKit accessory needs to
+// reflect and drive. colorTemperature is in mired (140-500), hue is in arc
+// degrees (0-360) and saturation is a percentage (0-100), matching the
+// HomeKit characteristics they back.
+type Light interface {
+	Name() string
+	CurrentState() (on bool, brightness int, colorTemperature int, hue float64, saturation float64)
+	SetTargetState(on bool, brightness int, colorTemperature int, hue float64, saturation float64) error
+	SetAutomatic(automatic bool)
+	Automatic() bool
+}
+
+// Bridge exposes a set of lights to HomeKit through a single bridge
+// accessory, as recommended for multi-accessory HomeKit integrations.
+type Bridge struct {
+	transport hc.Transport
+}
+
+// NewBridge creates a HomeKit bridge accessory exposing each given light
+// as a LightBulb accessory plus a companion Switch accessory used to
+// re-enable Kelvin's automatic control without opening the Hue app. Pin
+// and setup ID are persisted by the underlying transport inside
+// storagePath, alongside the bridge's pairing credentials.
+func NewBridge(name string, pin string, setupID string, storagePath string, lights []Light) (*Bridge, error) {
+	bridgeAccessory := accessory.NewBridge(accessory.Info{Name: name, Manufacturer: "Kelvin"})
+
+	var accessories []*accessory.Accessory
+	for index, light := range lights {
+		lightAccessory := newLightbulbAccessory(light, uint64(index*2+1))
+		toggleAccessory := newAutomaticToggleAccessory(light, uint64(index*2+2))
+		accessories = append(accessories, lightAccessory, toggleAccessory)
+	}
+
+	transport, err := hc.NewIPTransport(hc.Config{Pin: pin, SetupId: setupID, StoragePath: storagePath}, bridgeAccessory.Accessory, accessories...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Bridge{transport: transport}, nil
+}
+
+// Start exposes the bridge and its accessories on the local network. It
+// returns immediately - the transport runs in the background.
+func (bridge *Bridge) Start() {
+	go bridge.transport.Start()
+}
+
+// Stop removes the bridge from the local network and blocks until it has
+// shut down.
+func (bridge *Bridge) Stop() {
+	<-bridge.transport.Stop()
+}
+
+// newLightbulbAccessory creates a LightBulb accessory reflecting On,
+// Brightness, ColorTemperature, Hue and Saturation, wiring HomeKit writes
+// back into the same manual-override path as a change through the Hue app.
+func newLightbulbAccessory(light Light, id uint64) *accessory.Accessory {
+	info := accessory.Info{Name: light.Name(), Manufacturer: "Kelvin", ID: id}
+	bulb := accessory.NewLightbulb(info)
+
+	// The basic Lightbulb service only has an On characteristic by default,
+	// so Brightness, ColorTemperature, Hue and Saturation are attached
+	// here, the same way accessory.NewColoredLightbulb attaches
+	// Brightness, Hue and Saturation.
+	colorTemperature := characteristic.NewColorTemperature()
+	bulb.Lightbulb.AddCharacteristic(colorTemperature.Characteristic)
+	brightness := characteristic.NewBrightness()
+	bulb.Lightbulb.AddCharacteristic(brightness.Characteristic)
+	hue := characteristic.NewHue()
+	bulb.Lightbulb.AddCharacteristic(hue.Characteristic)
+	saturation := characteristic.NewSaturation()
+	bulb.Lightbulb.AddCharacteristic(saturation.Characteristic)
+
+	on, initialBrightness, initialColorTemperature, initialHue, initialSaturation := light.CurrentState()
+	bulb.Lightbulb.On.SetValue(on)
+	brightness.SetValue(initialBrightness)
+	colorTemperature.SetValue(initialColorTemperature)
+	hue.SetValue(initialHue)
+	saturation.SetValue(initialSaturation)
+
+	applyWrite := func() {
+		err := light.SetTargetState(bulb.Lightbulb.On.GetValue(), brightness.GetValue(), colorTemperature.GetValue(), hue.GetValue(), saturation.GetValue())
+		if err != nil {
+			log.Printf("🏠 HomeKit - Failed to apply state for light %s: %v", light.Name(), err)
+			return
+		}
+		light.SetAutomatic(false)
+	}
+	bulb.Lightbulb.On.OnValueRemoteUpdate(func(bool) { applyWrite() })
+	brightness.OnValueRemoteUpdate(func(int) { applyWrite() })
+	colorTemperature.OnValueRemoteUpdate(func(int) { applyWrite() })
+	hue.OnValueRemoteUpdate(func(float64) { applyWrite() })
+	saturation.OnValueRemoteUpdate(func(float64) { applyWrite() })
+
+	return bulb.Accessory
+}
+
+// newAutomaticToggleAccessory creates a Switch accessory that lets the
+// user re-enable Kelvin's automatic control of a light without having to
+// open the Hue app.
+func newAutomaticToggleAccessory(light Light, id uint64) *accessory.Accessory {
+	info := accessory.Info{Name: fmt.Sprintf("%s automatic", light.Name()), Manufacturer: "Kelvin", ID: id}
+	toggle := accessory.NewSwitch(info)
+	toggle.Switch.On.SetValue(light.Automatic())
+
+	toggle.Switch.On.OnValueRemoteUpdate(func(automatic bool) {
+		light.SetAutomatic(automatic)
+	})
+
+	return toggle.Accessory
+}
@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import "github.com/stefanwichmann/kelvin/homekit"
+
+// homeKitLight adapts a Light to the homekit.Light interface. Light can't
+// implement it directly because its Name field and the interface's Name()
+// method would collide.
+type homeKitLight struct {
+	light *Light
+}
+
+func (adapter homeKitLight) Name() string {
+	return adapter.light.Name
+}
+
+// CurrentState reports the light's state in the units the homekit package's
+// characteristics expect - colorTemperature in mired, hue in degrees and
+// saturation as a percentage - converting from LightState's Kelvin/Hue API
+// units.
+func (adapter homeKitLight) CurrentState() (on bool, brightness int, colorTemperature int, hue float64, saturation float64) {
+	state := adapter.light.Driver.CurrentState()
+	return adapter.light.On, state.Brightness, kelvinToMired(state.ColorTemperature), hueToDegrees(state.Hue), saturationToPercent(state.Saturation)
+}
+
+// SetTargetState applies a state change coming from the Home app.
+// colorTemperature arrives in mired, matching the homekit package's
+// ColorTemperature characteristic, and is converted back to Kelvin before
+// being stored. HomeKit always reports both ColorTemperature and
+// Hue/Saturation regardless of which one the user actually touched, so
+// saturation > 0 is taken to mean the user picked a color - the same
+// heuristic lifxColorToLightState uses to tell color states from
+// color-temperature ones.
+func (adapter homeKitLight) SetTargetState(on bool, brightness int, colorTemperature int, hue float64, saturation float64) error {
+	if !on {
+		brightness = 0
+	}
+
+	state := LightState{
+		Brightness:     brightness,
+		TransitionTime: defaultTransitionTime,
+	}
+	if saturation > 0 {
+		state.ColorMode = ColorModeHueSaturation
+		state.Hue = degreesToHue(hue)
+		state.Saturation = percentToSaturation(saturation)
+	} else {
+		state.ColorMode = ColorModeColorTemperature
+		state.ColorTemperature = miredToKelvin(colorTemperature)
+	}
+
+	return adapter.light.Driver.SetState(state)
+}
+
+// hueToDegrees and degreesToHue convert between LightState's Hue (0-65535,
+// matching the Hue API) and the HomeKit Hue characteristic (0-360 degrees).
+func hueToDegrees(hue uint16) float64 {
+	return float64(hue) / 65535 * 360
+}
+
+func degreesToHue(degrees float64) uint16 {
+	return uint16(degrees / 360 * 65535)
+}
+
+// saturationToPercent and percentToSaturation convert between LightState's
+// Saturation (0-254, matching the Hue API) and the HomeKit Saturation
+// characteristic (0-100%).
+func saturationToPercent(saturation uint8) float64 {
+	return float64(saturation) / 254 * 100
+}
+
+func percentToSaturation(percent float64) uint8 {
+	return uint8(percent / 100 * 254)
+}
+
+func (adapter homeKitLight) SetAutomatic(automatic bool) {
+	adapter.light.Automatic = automatic
+}
+
+func (adapter homeKitLight) Automatic() bool {
+	return adapter.light.Automatic
+}
+
+// homeKitLights adapts a slice of lights for homekit.NewBridge.
+func homeKitLights(lights []*Light) []homekit.Light {
+	adapters := make([]homekit.Light, len(lights))
+	for index, light := range lights {
+		adapters[index] = homeKitLight{light: light}
+	}
+	return adapters
+}
@@ -0,0 +1,233 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"strconv"
+
+	hue "github.com/stefanwichmann/go.hue"
+)
+
+// Hue bridges accept color temperature as mireds (micro reciprocal
+// degrees) through their "ct" field, while Kelvin represents color
+// temperature in Kelvin everywhere else (defaultColorTemperature, the log
+// output in light.go, schedule configuration). miredMin and miredMax are
+// the range the bridge itself accepts, per
+// http://developers.meethue.com/1_lightsapi.html#16_set_light_state.
+const (
+	miredMin = 153 // ~6500K, the coolest white Hue bulbs support.
+	miredMax = 500 // ~2000K, the warmest white Hue bulbs support.
+)
+
+// kelvinToMired converts a color temperature in Kelvin to the mired value
+// the bridge's "ct" field expects, clamped to the bridge's legal range.
+func kelvinToMired(kelvin int) int {
+	if kelvin <= 0 {
+		return miredMax
+	}
+
+	mired := 1000000 / kelvin
+	if mired < miredMin {
+		return miredMin
+	}
+	if mired > miredMax {
+		return miredMax
+	}
+	return mired
+}
+
+// miredToKelvin converts a mired value reported by the bridge back to Kelvin.
+func miredToKelvin(mired int) int {
+	if mired <= 0 {
+		return 0
+	}
+	return 1000000 / mired
+}
+
+// Capabilities describes which color representations a light supports,
+// as reported by the bridge.
+type Capabilities struct {
+	ColorTemperature bool
+	Color            bool // Hue/Saturation and xy.
+}
+
+// detectCapabilities maps a Hue light's "type" attribute to the color
+// representations it supports, as per
+// http://developers.meethue.com/1_lightsapi.html#16_set_light_state
+func detectCapabilities(lightType string) Capabilities {
+	switch lightType {
+	case "Extended color light":
+		return Capabilities{ColorTemperature: true, Color: true}
+	case "Color light":
+		return Capabilities{Color: true}
+	case "Color temperature light":
+		return Capabilities{ColorTemperature: true}
+	default:
+		return Capabilities{}
+	}
+}
+
+// constrain converts state down to a representation capabilities actually
+// supports, so a bulb is never sent hue/sat/xy or ct parameters it can't
+// display. Bulbs that support neither representation the state asks for
+// keep their brightness only, and let the bridge pick its own color.
+func (capabilities Capabilities) constrain(state LightState) LightState {
+	switch state.colorMode() {
+	case ColorModeHueSaturation, ColorModeXY:
+		if capabilities.Color {
+			return state
+		}
+	default:
+		if capabilities.ColorTemperature {
+			return state
+		}
+	}
+
+	if capabilities.ColorTemperature {
+		colorTemperature := state.ColorTemperature
+		if colorTemperature == 0 {
+			colorTemperature = defaultColorTemperature
+		}
+		return LightState{
+			ColorMode:        ColorModeColorTemperature,
+			ColorTemperature: colorTemperature,
+			Brightness:       state.Brightness,
+			TransitionTime:   state.TransitionTime,
+		}
+	}
+
+	return LightState{Brightness: state.Brightness, TransitionTime: state.TransitionTime}
+}
+
+// HueLight wraps a single Philips Hue light reachable through the given
+// bridge and keeps track of the state Kelvin last observed for it. It
+// implements the Driver interface.
+type HueLight struct {
+	Light           *hue.Light   `json:"-"`
+	ResourceID      string       `json:"-"` // v2 CLIP resource id (light.rid), used to subscribe to the bridge event stream.
+	currentState    LightState   `json:"-"`
+	lightCapability Capabilities `json:"-"`
+	reachable       bool         `json:"-"`
+	on              bool         `json:"-"`
+}
+
+// Capabilities reports which color representations this light supports.
+func (hueLight *HueLight) Capabilities() Capabilities {
+	return hueLight.lightCapability
+}
+
+// CurrentState returns the state Kelvin last observed for this light.
+func (hueLight *HueLight) CurrentState() LightState {
+	return hueLight.currentState
+}
+
+// Reachable reports whether the bridge last reported this light as reachable.
+func (hueLight *HueLight) Reachable() bool {
+	return hueLight.reachable
+}
+
+// On reports whether the bridge last reported this light as switched on.
+func (hueLight *HueLight) On() bool {
+	return hueLight.on
+}
+
+// hasState reports whether the light is already at the given state,
+// comparing brightness and whichever color representation state uses.
+// Color temperatures are compared in mired, the unit the bridge reports,
+// so the rounding and clamping of kelvinToMired never counts as a change.
+func (hueLight *HueLight) hasState(state LightState) bool {
+	current := hueLight.currentState
+	if current.colorMode() == ColorModeColorTemperature && state.colorMode() == ColorModeColorTemperature {
+		current.ColorTemperature = kelvinToMired(current.ColorTemperature)
+		state.ColorTemperature = kelvinToMired(state.ColorTemperature)
+	}
+	return current.equals(state)
+}
+
+// HasChanged reports whether the light was changed since Kelvin last set
+// its state, which happens when the user adjusted it manually (through
+// the Hue app, a wall switch, or a voice assistant).
+func (hueLight *HueLight) HasChanged() bool {
+	attr, err := hueLight.Light.GetLightAttributes()
+	if err != nil {
+		return false
+	}
+
+	return !hueLight.hasState(lightStateFromAttributes(attr.State))
+}
+
+// SetState sets the light's brightness and color, transitioning to the new
+// values over state.TransitionTime (or the package default). state is
+// converted down to a representation this light's Capabilities() actually
+// support before it's sent to the bridge.
+func (hueLight *HueLight) SetState(state LightState) error {
+	state = hueLight.lightCapability.constrain(state)
+
+	setState := hue.SetLightState{
+		On:             "true",
+		Bri:            strconv.Itoa(state.Brightness),
+		TransitionTime: strconv.Itoa(state.transitionTimeOrDefault()),
+	}
+
+	switch state.colorMode() {
+	case ColorModeHueSaturation:
+		setState.Hue = strconv.Itoa(int(state.Hue))
+		setState.Sat = strconv.Itoa(int(state.Saturation))
+	case ColorModeXY:
+		setState.Xy = state.XY[:]
+	default:
+		setState.Ct = strconv.Itoa(kelvinToMired(state.ColorTemperature))
+	}
+
+	_, err := hueLight.Light.SetState(setState)
+	if err != nil {
+		return err
+	}
+
+	hueLight.currentState = state
+	return nil
+}
+
+func (hueLight *HueLight) updateCurrentLightState(attr hue.LightAttributes) {
+	hueLight.reachable = attr.State.Reachable
+	hueLight.on = attr.State.On
+	hueLight.lightCapability = detectCapabilities(attr.Type)
+	hueLight.currentState = lightStateFromAttributes(attr.State)
+}
+
+// lightStateFromAttributes translates the state reported by the bridge
+// into a LightState, picking the color representation the bridge itself
+// reports as active.
+func lightStateFromAttributes(state hue.LightState) LightState {
+	switch state.ColorMode {
+	case "hs":
+		return LightState{ColorMode: ColorModeHueSaturation, Hue: uint16(state.Hue), Saturation: uint8(state.Sat), Brightness: state.Bri}
+	case "xy":
+		var xy [2]float32
+		if len(state.Xy) == 2 {
+			xy = [2]float32{state.Xy[0], state.Xy[1]}
+		}
+		return LightState{ColorMode: ColorModeXY, XY: xy, Brightness: state.Bri}
+	default:
+		return LightState{ColorMode: ColorModeColorTemperature, ColorTemperature: miredToKelvin(state.Ct), Brightness: state.Bri}
+	}
+}
@@ -0,0 +1,139 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import "time"
+
+// Interval describes a linear ramp of light state between two points in
+// time, e.g. dimming and warming the lights over the 20 minutes before
+// sunset.
+type Interval struct {
+	Start          SunTime
+	End            SunTime
+	StartState     LightState
+	EndState       LightState
+	TransitionTime int // Overrides the schedule's default transition time for this interval, 0 means "use the default".
+}
+
+// NewInterval creates an Interval between start and end, resolving
+// startColor and endColor - specified using ColorSpec's friendlier named
+// color or hex syntax - into the LightState representation
+// calculateLightStateInInterval interpolates between.
+func NewInterval(start SunTime, end SunTime, startColor ColorSpec, startBrightness int, endColor ColorSpec, endBrightness int, transitionTime int) (Interval, error) {
+	startState, err := startColor.resolve()
+	if err != nil {
+		return Interval{}, err
+	}
+	startState.Brightness = startBrightness
+
+	endState, err := endColor.resolve()
+	if err != nil {
+		return Interval{}, err
+	}
+	endState.Brightness = endBrightness
+
+	return Interval{
+		Start:          start,
+		End:            end,
+		StartState:     startState,
+		EndState:       endState,
+		TransitionTime: transitionTime,
+	}, nil
+}
+
+// calculateLightStateInInterval linearly interpolates the light state
+// between the interval's start and end state for the given point in time,
+// using whichever color representation the interval was configured with.
+func (interval Interval) calculateLightStateInInterval(now time.Time) LightState {
+	totalDuration := interval.End.Time.Sub(interval.Start.Time)
+	if totalDuration <= 0 {
+		return interval.EndState
+	}
+
+	progress := now.Sub(interval.Start.Time).Seconds() / totalDuration.Seconds()
+	if progress < 0 {
+		progress = 0
+	}
+	if progress > 1 {
+		progress = 1
+	}
+
+	// Hue/saturation, xy and color temperature are different color spaces,
+	// so there's no meaningful way to interpolate between a start and end
+	// state that don't share a color mode. The light holds the start
+	// state's color and switches straight to the end state once the
+	// interval completes, rather than interpolating across mismatched
+	// fields.
+	if interval.StartState.colorMode() != interval.EndState.colorMode() {
+		if progress >= 1 {
+			return interval.EndState
+		}
+		state := interval.StartState
+		state.Brightness = lerpInt(interval.StartState.Brightness, interval.EndState.Brightness, progress)
+		state.TransitionTime = interval.transitionTime()
+		return state
+	}
+
+	state := LightState{
+		Brightness:     lerpInt(interval.StartState.Brightness, interval.EndState.Brightness, progress),
+		TransitionTime: interval.transitionTime(),
+	}
+
+	switch interval.StartState.colorMode() {
+	case ColorModeHueSaturation:
+		state.ColorMode = ColorModeHueSaturation
+		state.Hue = uint16(lerpInt(int(interval.StartState.Hue), int(interval.EndState.Hue), progress))
+		state.Saturation = uint8(lerpInt(int(interval.StartState.Saturation), int(interval.EndState.Saturation), progress))
+	case ColorModeXY:
+		state.ColorMode = ColorModeXY
+		state.XY = [2]float32{
+			float32(lerpFloat(float64(interval.StartState.XY[0]), float64(interval.EndState.XY[0]), progress)),
+			float32(lerpFloat(float64(interval.StartState.XY[1]), float64(interval.EndState.XY[1]), progress)),
+		}
+	default:
+		state.ColorMode = ColorModeColorTemperature
+		state.ColorTemperature = lerpInt(interval.StartState.ColorTemperature, interval.EndState.ColorTemperature, progress)
+	}
+
+	return state
+}
+
+// lerpInt linearly interpolates between start and end at the given
+// progress (0-1).
+func lerpInt(start int, end int, progress float64) int {
+	return start + int(float64(end-start)*progress)
+}
+
+// lerpFloat linearly interpolates between start and end at the given
+// progress (0-1).
+func lerpFloat(start float64, end float64, progress float64) float64 {
+	return start + (end-start)*progress
+}
+
+// transitionTime returns the interval's own transition time override, or
+// falls back to the package default.
+func (interval Interval) transitionTime() int {
+	if interval.TransitionTime > 0 {
+		return interval.TransitionTime
+	}
+	return defaultTransitionTime
+}
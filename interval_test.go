@@ -0,0 +1,121 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCalculateLightStateInIntervalInterpolatesColorTemperature(t *testing.T) {
+	start := time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(20 * time.Minute)
+	interval := Interval{
+		Start:      SunTime{Time: start},
+		End:        SunTime{Time: end},
+		StartState: LightState{ColorMode: ColorModeColorTemperature, ColorTemperature: 200, Brightness: 100},
+		EndState:   LightState{ColorMode: ColorModeColorTemperature, ColorTemperature: 300, Brightness: 50},
+	}
+
+	state := interval.calculateLightStateInInterval(start.Add(10 * time.Minute))
+	if state.ColorTemperature != 250 {
+		t.Fatalf("expected color temperature 250 halfway through, got %v", state.ColorTemperature)
+	}
+	if state.Brightness != 75 {
+		t.Fatalf("expected brightness 75 halfway through, got %v", state.Brightness)
+	}
+}
+
+func TestCalculateLightStateInIntervalClampsProgress(t *testing.T) {
+	start := time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(20 * time.Minute)
+	interval := Interval{
+		Start:      SunTime{Time: start},
+		End:        SunTime{Time: end},
+		StartState: LightState{ColorTemperature: 200, Brightness: 100},
+		EndState:   LightState{ColorTemperature: 300, Brightness: 50},
+	}
+
+	before := interval.calculateLightStateInInterval(start.Add(-time.Minute))
+	if !before.equals(interval.StartState) {
+		t.Fatalf("expected the start state before the interval begins, got %+v", before)
+	}
+
+	after := interval.calculateLightStateInInterval(end.Add(time.Minute))
+	if !after.equals(interval.EndState) {
+		t.Fatalf("expected the end state after the interval ends, got %+v", after)
+	}
+}
+
+func TestCalculateLightStateInIntervalHoldsStartColorAcrossMismatchedModes(t *testing.T) {
+	start := time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)
+	end := start.Add(20 * time.Minute)
+	interval := Interval{
+		Start:      SunTime{Time: start},
+		End:        SunTime{Time: end},
+		StartState: LightState{ColorMode: ColorModeColorTemperature, ColorTemperature: 300, Brightness: 100},
+		EndState:   LightState{ColorMode: ColorModeHueSaturation, Hue: 100, Saturation: 200, Brightness: 50},
+	}
+
+	midway := interval.calculateLightStateInInterval(start.Add(10 * time.Minute))
+	if midway.ColorMode != ColorModeColorTemperature || midway.ColorTemperature != 300 {
+		t.Fatalf("expected the start state's color to be held midway through a mismatched-mode interval, got %+v", midway)
+	}
+	if midway.Brightness != 75 {
+		t.Fatalf("expected brightness to still interpolate, got %v", midway.Brightness)
+	}
+
+	atEnd := interval.calculateLightStateInInterval(end)
+	if !atEnd.equals(interval.EndState) {
+		t.Fatalf("expected the end state once the interval completes, got %+v", atEnd)
+	}
+}
+
+func TestNewIntervalResolvesColorSpecs(t *testing.T) {
+	start := SunTime{Time: time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)}
+	end := SunTime{Time: start.Time.Add(20 * time.Minute)}
+
+	interval, err := NewInterval(start, end, ColorSpec{ColorTemperature: 400}, 100, ColorSpec{Color: "orange"}, 50, 5)
+	if err != nil {
+		t.Fatalf("NewInterval returned error: %v", err)
+	}
+
+	if interval.StartState.ColorMode != ColorModeColorTemperature || interval.StartState.ColorTemperature != 400 || interval.StartState.Brightness != 100 {
+		t.Fatalf("expected the start color spec to resolve into StartState, got %+v", interval.StartState)
+	}
+	if interval.EndState.ColorMode != ColorModeHueSaturation || interval.EndState.Brightness != 50 {
+		t.Fatalf("expected the end color spec to resolve into EndState, got %+v", interval.EndState)
+	}
+	if interval.TransitionTime != 5 {
+		t.Fatalf("expected transition time 5, got %v", interval.TransitionTime)
+	}
+}
+
+func TestNewIntervalPropagatesResolveError(t *testing.T) {
+	start := SunTime{Time: time.Date(2020, 1, 1, 18, 0, 0, 0, time.UTC)}
+	end := SunTime{Time: start.Time.Add(20 * time.Minute)}
+
+	_, err := NewInterval(start, end, ColorSpec{Color: "notacolor"}, 100, ColorSpec{}, 50, 0)
+	if err == nil {
+		t.Fatal("expected an error for an unresolvable start color")
+	}
+}
@@ -0,0 +1,214 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"time"
+
+	"github.com/pdf/golifx"
+	"github.com/pdf/golifx/common"
+)
+
+// lifxMinKelvin and lifxMaxKelvin are the color temperature range LIFX
+// bulbs understand, as per https://lan.developer.lifx.com.
+const (
+	lifxMinKelvin = 2500
+	lifxMaxKelvin = 9000
+)
+
+// LifxLight wraps a single LIFX light found on the local network over the
+// LIFX LAN protocol and keeps track of the state Kelvin last observed for
+// it. It implements the Driver interface.
+type LifxLight struct {
+	light        common.Light
+	currentState LightState
+	reachable    bool
+	on           bool
+}
+
+// NewLifxLight looks up a LIFX light by its label (the name assigned to it
+// in the LIFX app) through client, Kelvin's shared LIFX LAN client, and
+// primes its current state.
+func NewLifxLight(client *golifx.Client, label string) (*LifxLight, error) {
+	light, err := client.GetLightByLabel(label)
+	if err != nil {
+		return nil, err
+	}
+
+	lifxLight := &LifxLight{light: light}
+	lifxLight.refresh()
+	return lifxLight, nil
+}
+
+// Capabilities reports which color representations this light supports.
+// LIFX's color bulbs all support both color temperature and full color.
+func (lifxLight *LifxLight) Capabilities() Capabilities {
+	return Capabilities{ColorTemperature: true, Color: true}
+}
+
+// CurrentState returns the state Kelvin last observed for this light.
+func (lifxLight *LifxLight) CurrentState() LightState {
+	return lifxLight.currentState
+}
+
+// Reachable reports whether the light last responded to a LAN request.
+func (lifxLight *LifxLight) Reachable() bool {
+	return lifxLight.reachable
+}
+
+// On reports whether the light is currently switched on.
+func (lifxLight *LifxLight) On() bool {
+	return lifxLight.on
+}
+
+// HasChanged reports whether the light was changed since Kelvin last set
+// its state, which happens when the user adjusted it through the LIFX app,
+// a physical switch or a voice assistant.
+func (lifxLight *LifxLight) HasChanged() bool {
+	state, err := lifxLight.fetchState()
+	if err != nil {
+		lifxLight.reachable = false
+		return false
+	}
+	lifxLight.reachable = true
+
+	// The reported state went through the HSBK scaling and clamping, so the
+	// last set state is compared after the same round trip.
+	expected := lifxColorToLightState(lightStateToLifxColor(lifxLight.currentState))
+	return !expected.equals(state)
+}
+
+// SetState sets the light's brightness and color, transitioning to the new
+// values over state.TransitionTime (deciseconds, converted to the
+// time.Duration golifx expects).
+func (lifxLight *LifxLight) SetState(state LightState) error {
+	transitionTime := time.Duration(state.transitionTimeOrDefault()) * 100 * time.Millisecond
+	on := state.Brightness > 0
+
+	if err := lifxLight.light.SetPowerDuration(on, transitionTime); err != nil {
+		return err
+	}
+	lifxLight.on = on
+
+	if !on {
+		lifxLight.currentState = state
+		return nil
+	}
+
+	if err := lifxLight.light.SetColor(lightStateToLifxColor(state), transitionTime); err != nil {
+		return err
+	}
+
+	lifxLight.currentState = state
+	lifxLight.reachable = true
+	return nil
+}
+
+// refresh polls the light for its current power and color state.
+func (lifxLight *LifxLight) refresh() {
+	state, err := lifxLight.fetchState()
+	if err != nil {
+		lifxLight.reachable = false
+		return
+	}
+
+	lifxLight.currentState = state
+	lifxLight.reachable = true
+}
+
+func (lifxLight *LifxLight) fetchState() (LightState, error) {
+	on, err := lifxLight.light.GetPower()
+	if err != nil {
+		return LightState{}, err
+	}
+	lifxLight.on = on
+
+	color, err := lifxLight.light.GetColor()
+	if err != nil {
+		return LightState{}, err
+	}
+
+	return lifxColorToLightState(color), nil
+}
+
+// lightStateToLifxColor converts a LightState into the HSBK representation
+// golifx expects. CIE xy isn't representable on LIFX's protocol, so xy
+// states fall back to color temperature only, same as a CT-only Hue bulb
+// would when asked to show an xy-specified scene.
+func lightStateToLifxColor(state LightState) common.Color {
+	kelvin := state.ColorTemperature
+	if kelvin < lifxMinKelvin {
+		kelvin = lifxMinKelvin
+	}
+	if kelvin > lifxMaxKelvin {
+		kelvin = lifxMaxKelvin
+	}
+
+	color := common.Color{
+		Kelvin:     uint16(kelvin),
+		Brightness: scaleToUint16(state.Brightness, 100),
+	}
+
+	if state.colorMode() == ColorModeHueSaturation {
+		color.Hue = state.Hue
+		color.Saturation = scaleToUint16(int(state.Saturation), 254)
+	}
+
+	return color
+}
+
+// lifxColorToLightState converts golifx's HSBK representation back into a
+// LightState, picking color temperature or hue/saturation depending on
+// whether the bulb is currently showing a saturated color.
+func lifxColorToLightState(color common.Color) LightState {
+	if color.Saturation == 0 {
+		return LightState{
+			ColorMode:        ColorModeColorTemperature,
+			ColorTemperature: int(color.Kelvin),
+			Brightness:       scaleFromUint16(color.Brightness, 100),
+		}
+	}
+
+	return LightState{
+		ColorMode:  ColorModeHueSaturation,
+		Hue:        color.Hue,
+		Saturation: uint8(scaleFromUint16(color.Saturation, 254)),
+		Brightness: scaleFromUint16(color.Brightness, 100),
+	}
+}
+
+// scaleToUint16 scales value (0-max) to golifx's 0-65535 range.
+func scaleToUint16(value int, max int) uint16 {
+	if value <= 0 {
+		return 0
+	}
+	if value >= max {
+		return 65535
+	}
+	return uint16(value * 65535 / max)
+}
+
+// scaleFromUint16 scales a golifx 0-65535 value down to the 0-max range
+// Kelvin uses internally.
+func scaleFromUint16(value uint16, max int) int {
+	return int(value) * max / 65535
+}
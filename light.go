@@ -32,7 +32,7 @@ import (
 type Light struct {
 	ID               int        `json:"id"`
 	Name             string     `json:"name"`
-	HueLight         HueLight   `json:"-"`
+	Driver           Driver     `json:"-"`
 	TargetLightState LightState `json:"targetLightState,omitempty"`
 	Scheduled        bool       `json:"scheduled"`
 	Reachable        bool       `json:"reachable"`
@@ -42,15 +42,46 @@ type Light struct {
 	Schedule         Schedule   `json:"-"`
 	Interval         Interval   `json:"interval"`
 	Appearance       time.Time  `json:"-"`
+	updates          chan hue.LightAttributes
 }
 
+// updateCurrentLightState applies a state pushed by the Hue bridge event
+// stream. It is a no-op for lights driven by anything other than Hue, since
+// only the Hue bridge pushes updates this way - other drivers keep relying
+// on the regular polling cycle in update().
 func (light *Light) updateCurrentLightState(attr hue.LightAttributes) error {
-	light.HueLight.updateCurrentLightState(attr)
-	light.Reachable = light.HueLight.Reachable
-	light.On = light.HueLight.On
+	hueLight, ok := light.Driver.(*HueLight)
+	if !ok {
+		return nil
+	}
+
+	hueLight.updateCurrentLightState(attr)
+	light.Reachable = hueLight.Reachable()
+	light.On = hueLight.On()
 	return nil
 }
 
+// subscribeToEventStream registers this light with the bridge event stream
+// so manual changes are reflected immediately instead of waiting for the
+// next polling cycle. lightResourceID is the light's v2 CLIP resource ID.
+// Bridges that don't support the event stream (v1 bridges, or a stream
+// that keeps disconnecting) simply never send anything, and Kelvin keeps
+// relying on its regular polling interval as before.
+func (light *Light) subscribeToEventStream(stream *EventStream, lightResourceID string) {
+	light.updates = stream.Subscribe(lightResourceID)
+	go light.listenForPushUpdates()
+}
+
+// listenForPushUpdates applies light attribute changes pushed by the
+// bridge event stream as soon as they arrive. It returns once the
+// underlying channel is closed.
+func (light *Light) listenForPushUpdates() {
+	for attr := range light.updates {
+		log.Debugf("💡 Light %s - Received pushed state update from event stream", light.Name)
+		light.updateCurrentLightState(attr)
+	}
+}
+
 func (light *Light) update() (bool, error) {
 	// Is the light associated to any schedule?
 	if !light.Scheduled {
@@ -93,7 +124,7 @@ func (light *Light) update() (bool, error) {
 		if light.Schedule.enableWhenLightsAppear {
 			log.Printf("💡 Light %s - Initializing state to %vK at %v%% brightness.", light.Name, light.TargetLightState.ColorTemperature, light.TargetLightState.Brightness)
 
-			err := light.HueLight.setLightState(light.TargetLightState.ColorTemperature, light.TargetLightState.Brightness)
+			err := light.Driver.SetState(light.TargetLightState)
 			if err != nil {
 				return false, err
 			}
@@ -113,12 +144,12 @@ func (light *Light) update() (bool, error) {
 		}
 
 		// if status == scene state --> Activate Kelvin
-		if light.HueLight.hasState(light.TargetLightState.ColorTemperature, light.TargetLightState.Brightness) {
+		if light.Driver.CurrentState().equals(light.TargetLightState) {
 			log.Printf("💡 Light %s - Detected matching target state. Activating Kelvin...", light.Name)
 			light.Automatic = true
 
-			// set correct target lightstate on HueLight
-			err := light.HueLight.setLightState(light.TargetLightState.ColorTemperature, light.TargetLightState.Brightness)
+			// set correct target lightstate on the driver
+			err := light.Driver.SetState(light.TargetLightState)
 			if err != nil {
 				return false, err
 			}
@@ -127,9 +158,9 @@ func (light *Light) update() (bool, error) {
 	}
 
 	// Did the user manually change the light state?
-	if light.HueLight.hasChanged() {
+	if light.Driver.HasChanged() {
 		if log.GetLevel() == log.DebugLevel {
-			log.Debugf("💡 Light %s - Light state has been changed manually after %v: %+v", light.Name, time.Since(light.Appearance), light.HueLight)
+			log.Debugf("💡 Light %s - Light state has been changed manually after %v: %+v", light.Name, time.Since(light.Appearance), light.Driver)
 		} else {
 			log.Printf("💡 Light %s - Light state has been changed manually. Disabling Kelvin...", light.Name)
 		}
@@ -138,12 +169,12 @@ func (light *Light) update() (bool, error) {
 	}
 
 	// Update of lightstate needed?
-	if light.HueLight.hasState(light.TargetLightState.ColorTemperature, light.TargetLightState.Brightness) {
+	if light.Driver.CurrentState().equals(light.TargetLightState) {
 		return false, nil
 	}
 
 	// Light is turned on and in automatic state. Set target lightstate.
-	err := light.HueLight.setLightState(light.TargetLightState.ColorTemperature, light.TargetLightState.Brightness)
+	err := light.Driver.SetState(light.TargetLightState)
 	if err != nil {
 		return false, err
 	}
@@ -152,6 +183,30 @@ func (light *Light) update() (bool, error) {
 	return true, nil
 }
 
+// readyForUpdate reports whether the light is eligible for a state update
+// right now: scheduled, reachable, switched on, under Kelvin's automatic
+// control, not changed manually and not already at its target light
+// state. It mirrors the gating performed by update() without setting any
+// state (it does query the driver through HasChanged()), so the scheduler
+// can decide whether several lights can be batched into a single request.
+func (light *Light) readyForUpdate() bool {
+	if !(light.Scheduled && light.Reachable && light.On && light.Tracking && light.Automatic) {
+		return false
+	}
+
+	// Did the user manually change the light state? The scheduler calls
+	// readyForUpdate() instead of update() for lights it intends to batch,
+	// so this check has to be duplicated here - otherwise a light the user
+	// just adjusted by hand would be swept into the next batch and have
+	// its manual override silently reverted.
+	if light.Driver.HasChanged() {
+		light.Automatic = false
+		return false
+	}
+
+	return !light.Driver.CurrentState().equals(light.TargetLightState)
+}
+
 func (light *Light) updateSchedule(schedule Schedule) {
 	light.Schedule = schedule
 	light.Scheduled = true
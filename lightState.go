@@ -0,0 +1,93 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+// defaultTransitionTime is the transition time (in deciseconds, as used by
+// the Hue API) applied when neither a schedule nor an interval specifies
+// its own value. 4 deciseconds is the bridge's own default.
+const defaultTransitionTime = 4
+
+// defaultColorTemperature is the color temperature a color state falls back
+// to when it has to be converted down for a bulb that can't represent
+// color, and the state itself carries no color temperature of its own.
+const defaultColorTemperature = 2700
+
+// ColorMode identifies which of LightState's color representations is
+// active, mirroring the Hue API's own "colormode" attribute.
+type ColorMode string
+
+// The three color representations the Hue API understands. Color-capable
+// bulbs report whichever one they were last set through; color
+// temperature bulbs only ever use ColorModeColorTemperature.
+const (
+	ColorModeColorTemperature ColorMode = "ct"
+	ColorModeHueSaturation    ColorMode = "hs"
+	ColorModeXY               ColorMode = "xy"
+)
+
+// LightState represents the state Kelvin wants a light to be in at a
+// given point in time. Exactly one color representation is meaningful at
+// a time, selected by ColorMode - the others are left at their zero value.
+type LightState struct {
+	ColorMode        ColorMode  `json:"colorMode,omitempty"`
+	ColorTemperature int        `json:"colorTemperature,omitempty"`
+	Hue              uint16     `json:"hue,omitempty"`
+	Saturation       uint8      `json:"saturation,omitempty"`
+	XY               [2]float32 `json:"xy,omitempty"`
+	Brightness       int        `json:"brightness"`
+	TransitionTime   int        `json:"transitionTime,omitempty"` // In deciseconds, matching the Hue API.
+}
+
+// equals reports whether two states describe the same brightness and
+// color, comparing only the fields relevant to the active color mode.
+func (state LightState) equals(other LightState) bool {
+	if state.Brightness != other.Brightness || state.colorMode() != other.colorMode() {
+		return false
+	}
+
+	switch state.colorMode() {
+	case ColorModeHueSaturation:
+		return state.Hue == other.Hue && state.Saturation == other.Saturation
+	case ColorModeXY:
+		return state.XY == other.XY
+	default:
+		return state.ColorTemperature == other.ColorTemperature
+	}
+}
+
+// colorMode returns the state's color mode, defaulting to color
+// temperature for states that predate ColorMode (or never set it).
+func (state LightState) colorMode() ColorMode {
+	if state.ColorMode == "" {
+		return ColorModeColorTemperature
+	}
+	return state.ColorMode
+}
+
+// transitionTimeOrDefault returns the state's transition time, falling
+// back to defaultTransitionTime if none was set.
+func (state LightState) transitionTimeOrDefault() int {
+	if state.TransitionTime > 0 {
+		return state.TransitionTime
+	}
+	return defaultTransitionTime
+}
@@ -0,0 +1,64 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import "testing"
+
+func TestLightStateEqualsComparesActiveColorModeOnly(t *testing.T) {
+	a := LightState{ColorMode: ColorModeHueSaturation, Hue: 100, Saturation: 200, Brightness: 50}
+	b := LightState{ColorMode: ColorModeHueSaturation, Hue: 100, Saturation: 200, Brightness: 50, ColorTemperature: 400}
+	if !a.equals(b) {
+		t.Fatalf("expected states to be equal despite differing in an inactive field, got %+v vs %+v", a, b)
+	}
+}
+
+func TestLightStateEqualsDiffersOnBrightness(t *testing.T) {
+	a := LightState{Brightness: 50}
+	b := LightState{Brightness: 51}
+	if a.equals(b) {
+		t.Fatal("expected states with different brightness to compare unequal")
+	}
+}
+
+func TestLightStateEqualsDiffersOnColorMode(t *testing.T) {
+	a := LightState{ColorMode: ColorModeColorTemperature, ColorTemperature: 300, Brightness: 50}
+	b := LightState{ColorMode: ColorModeHueSaturation, Hue: 100, Saturation: 200, Brightness: 50}
+	if a.equals(b) {
+		t.Fatal("expected states with different color modes to compare unequal")
+	}
+}
+
+func TestLightStateColorModeDefaultsToColorTemperature(t *testing.T) {
+	state := LightState{}
+	if state.colorMode() != ColorModeColorTemperature {
+		t.Fatalf("expected zero-value state to default to ColorModeColorTemperature, got %v", state.colorMode())
+	}
+}
+
+func TestLightStateTransitionTimeOrDefault(t *testing.T) {
+	if got := (LightState{}).transitionTimeOrDefault(); got != defaultTransitionTime {
+		t.Fatalf("expected defaultTransitionTime for a zero-value state, got %v", got)
+	}
+	if got := (LightState{TransitionTime: 10}).transitionTimeOrDefault(); got != 10 {
+		t.Fatalf("expected the state's own transition time to take precedence, got %v", got)
+	}
+}
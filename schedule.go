@@ -0,0 +1,61 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"errors"
+	"time"
+)
+
+// SunTime represents a point in time tied to a solar event (sunrise or
+// sunset) rather than a fixed wall clock time.
+type SunTime struct {
+	Time time.Time
+}
+
+// Schedule describes how a light should behave over the course of a day,
+// expressed as a series of intervals between solar and/or fixed times.
+type Schedule struct {
+	sunrise                SunTime
+	sunset                 SunTime
+	endOfDay               time.Time
+	enableWhenLightsAppear bool
+	defaultTransitionTime  int // Applied to every interval that doesn't specify its own.
+	intervals              []Interval
+}
+
+// currentInterval returns the interval active at the given time, or an
+// error if none of the schedule's intervals cover it. An interval that
+// doesn't specify its own TransitionTime inherits the schedule's
+// defaultTransitionTime (itself optional - Interval.transitionTime() falls
+// back further to the package default if neither was set).
+func (schedule Schedule) currentInterval(now time.Time) (Interval, error) {
+	for _, interval := range schedule.intervals {
+		if (now.Equal(interval.Start.Time) || now.After(interval.Start.Time)) && now.Before(interval.End.Time) {
+			if interval.TransitionTime == 0 {
+				interval.TransitionTime = schedule.defaultTransitionTime
+			}
+			return interval, nil
+		}
+	}
+	return Interval{}, errors.New("no active interval for the given time")
+}
@@ -0,0 +1,253 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	log "github.com/Sirupsen/logrus"
+	hue "github.com/stefanwichmann/go.hue"
+)
+
+// batchGroupName is the Hue group Kelvin creates temporarily to fan a
+// single state change out to several bulbs over Zigbee in one request.
+// go.hue has no native group support, so Kelvin talks to the bridge's
+// /groups endpoint directly through bridgeRequest, while HueLight reaches
+// /lights through go.hue.
+const batchGroupName = "Kelvin batch update"
+
+// batchKey returns a copy of state suitable as a map key for grouping
+// lights that should receive an identical PUT, so the bridge can be
+// addressed with a single request instead of one per light. Transition
+// time is excluded - lights sharing a target color/brightness can still
+// be batched even with different transition times; the group uses the
+// shortest one of the batch.
+func batchKey(state LightState) LightState {
+	state.TransitionTime = 0
+	return state
+}
+
+// batchMetrics tracks how many individual Hue API calls batching saved,
+// so the savings can be logged or exposed alongside other Kelvin metrics.
+type batchMetrics struct {
+	mutex       sync.Mutex
+	CallsSaved  int
+	BatchesSent int
+}
+
+func (metrics *batchMetrics) recordBatch(lightsInBatch int) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.BatchesSent++
+	metrics.CallsSaved += lightsInBatch - 1
+}
+
+var scheduledUpdateMetrics batchMetrics
+
+// updateLights applies each light's target state. When batchUpdates is
+// enabled, lights that are ready for an update and share an identical
+// target state are grouped and updated through a single temporary Hue
+// group instead of one request per light, trading a little bit of
+// latency on the bridge side for a lot fewer REST calls. Batching is a
+// Hue bridge feature, so only lights driven by HueLight are eligible -
+// every other driver always updates individually. Every light not pulled
+// into a successfully-batched group still goes through update() via
+// updateLightsIndividually, so its state machine (appearance, losing
+// reachability, manual overrides) keeps running whether or not it was
+// eligible for batching.
+func updateLights(lights []*Light, bridge *hue.Bridge, batchUpdates bool) error {
+	if !batchUpdates {
+		return updateLightsIndividually(lights)
+	}
+
+	groups := make(map[LightState][]*Light)
+	var remaining []*Light
+	for _, light := range lights {
+		if !light.readyForUpdate() {
+			remaining = append(remaining, light)
+			continue
+		}
+		hueLight, ok := light.Driver.(*HueLight)
+		if !ok {
+			remaining = append(remaining, light)
+			continue
+		}
+
+		// Constrain the target state to this light's own capabilities
+		// before computing its batch key, so a CT-only bulb and a
+		// color-capable bulb that happen to share a raw TargetLightState
+		// (e.g. an interval using HS/XY) never end up grouped under a
+		// state the CT-only bulb can't actually reach.
+		key := batchKey(hueLight.Capabilities().constrain(light.TargetLightState))
+		groups[key] = append(groups[key], light)
+	}
+
+	for key, group := range groups {
+		if len(group) < 2 {
+			// Not worth creating a group for a single light.
+			remaining = append(remaining, group...)
+			continue
+		}
+
+		state := key
+		for _, light := range group {
+			transitionTime := light.TargetLightState.TransitionTime
+			if transitionTime > 0 && (state.TransitionTime == 0 || transitionTime < state.TransitionTime) {
+				state.TransitionTime = transitionTime
+			}
+		}
+
+		err := updateLightGroup(bridge, group, state)
+		if err != nil {
+			log.Printf("💡 Batch update - Failed to update group of %v lights, falling back to individual updates: %v", len(group), err)
+			remaining = append(remaining, group...)
+			continue
+		}
+
+		scheduledUpdateMetrics.recordBatch(len(group))
+		log.Debugf("💡 Batch update - Updated %v lights to %+v with a single bridge request", len(group), state)
+	}
+
+	return updateLightsIndividually(remaining)
+}
+
+func updateLightsIndividually(lights []*Light) error {
+	for _, light := range lights {
+		if _, err := light.update(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateLightGroup creates a temporary Hue group containing the given
+// lights, sets its state in a single request and removes the group again.
+func updateLightGroup(bridge *hue.Bridge, lights []*Light, state LightState) error {
+	lightIDs := make([]string, len(lights))
+	for index, light := range lights {
+		lightIDs[index] = light.Driver.(*HueLight).Light.Id
+	}
+
+	groupID, err := createTemporaryGroup(bridge, lightIDs)
+	if err != nil {
+		return err
+	}
+	defer deleteGroup(bridge, groupID)
+
+	err = setGroupLightState(bridge, groupID, state)
+	if err != nil {
+		return err
+	}
+
+	for _, light := range lights {
+		light.Driver.(*HueLight).currentState = state
+	}
+	return nil
+}
+
+func createTemporaryGroup(bridge *hue.Bridge, lightIDs []string) (string, error) {
+	request := map[string]interface{}{
+		"name":   batchGroupName,
+		"lights": lightIDs,
+		"type":   "LightGroup",
+		"class":  "TemporaryHueGroup",
+	}
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+
+	var results []struct {
+		Success struct {
+			ID string `json:"id"`
+		} `json:"success"`
+	}
+	err = bridgeRequest(bridge, "POST", "/groups", body, &results)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 || results[0].Success.ID == "" {
+		return "", fmt.Errorf("bridge did not return a group id")
+	}
+	return results[0].Success.ID, nil
+}
+
+func setGroupLightState(bridge *hue.Bridge, groupID string, state LightState) error {
+	params := map[string]interface{}{
+		"on":             true,
+		"bri":            state.Brightness,
+		"transitiontime": state.transitionTimeOrDefault(),
+	}
+	switch state.colorMode() {
+	case ColorModeHueSaturation:
+		params["hue"] = state.Hue
+		params["sat"] = state.Saturation
+	case ColorModeXY:
+		params["xy"] = state.XY
+	default:
+		params["ct"] = kelvinToMired(state.ColorTemperature)
+	}
+
+	body, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return bridgeRequest(bridge, "PUT", "/groups/"+groupID+"/action", body, nil)
+}
+
+func deleteGroup(bridge *hue.Bridge, groupID string) error {
+	return bridgeRequest(bridge, "DELETE", "/groups/"+groupID, nil, nil)
+}
+
+// bridgeRequest issues a raw request against the bridge's v1 REST API,
+// for the parts of it (groups) go.hue doesn't wrap itself.
+func bridgeRequest(bridge *hue.Bridge, method string, path string, body []byte, result interface{}) error {
+	url := fmt.Sprintf("http://%s/api/%s%s", bridge.IpAddr, bridge.Username, path)
+
+	var reader *bytes.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	} else {
+		reader = bytes.NewReader([]byte{})
+	}
+
+	request, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if result != nil {
+		return json.NewDecoder(response.Body).Decode(result)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+// MIT License
+//
+// Copyright (c) 2019 Stefan Wichmann
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+package main
+
+import "testing"
+
+func TestBatchKeyIgnoresTransitionTime(t *testing.T) {
+	a := batchKey(LightState{ColorTemperature: 300, Brightness: 100, TransitionTime: 4})
+	b := batchKey(LightState{ColorTemperature: 300, Brightness: 100, TransitionTime: 10})
+
+	if a != b {
+		t.Fatalf("expected states differing only in transition time to share a batch key, got %+v vs %+v", a, b)
+	}
+}
+
+func TestBatchKeyDistinguishesTargetState(t *testing.T) {
+	a := batchKey(LightState{ColorTemperature: 300, Brightness: 100})
+	b := batchKey(LightState{ColorTemperature: 301, Brightness: 100})
+
+	if a == b {
+		t.Fatalf("expected states with different color temperatures to have different batch keys, got %+v", a)
+	}
+}
+
+func TestBatchMetricsRecordBatch(t *testing.T) {
+	var metrics batchMetrics
+	metrics.recordBatch(3)
+	metrics.recordBatch(2)
+
+	if metrics.BatchesSent != 2 {
+		t.Fatalf("expected 2 batches sent, got %v", metrics.BatchesSent)
+	}
+	if metrics.CallsSaved != 3 {
+		t.Fatalf("expected 3 calls saved (2+1), got %v", metrics.CallsSaved)
+	}
+}